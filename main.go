@@ -0,0 +1,17 @@
+package main
+
+import (
+	srplugin "github.com/go-semantic-release/semantic-release/v2/pkg/plugin"
+	srprovider "github.com/go-semantic-release/semantic-release/v2/pkg/provider"
+
+	"github.com/durandj/provider-git/pkg/provider"
+)
+
+func main() {
+	repo := &provider.Repository{}
+	defer repo.Close()
+
+	srplugin.Serve(&srplugin.ServeOpts{
+		Provider: func() srprovider.Provider { return repo },
+	})
+}
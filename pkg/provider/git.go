@@ -0,0 +1,862 @@
+// Package provider implements a go-semantic-release provider that works
+// directly against a local git repository checked out on disk. It doesn't
+// require a hosted git service API, though it does talk to the "origin"
+// remote: GetCommits/GetReleases best-effort sync from it first (see
+// fetchMode), and CreateRelease always pushes the release tag there.
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/go-semantic-release/semantic-release/v2/pkg/provider"
+	"github.com/go-semantic-release/semantic-release/v2/pkg/semrel"
+)
+
+const (
+	defaultBranchName        = "master"
+	defaultAuthSSHUsername   = "git"
+	defaultAuthTokenUsername = "x-access-token"
+	defaultSignTagsMode      = signTagsIfKey
+	defaultFetchMode         = fetchAuto
+)
+
+// signTagsMode controls whether CreateRelease produces a PGP-signed
+// annotated tag.
+type signTagsMode string
+
+const (
+	signTagsAlways signTagsMode = "always"
+	signTagsNever  signTagsMode = "never"
+	signTagsIfKey  signTagsMode = "ifkey"
+)
+
+// fetchMode controls whether GetCommits/GetReleases sync from "origin"
+// before reading. Repository is primarily a local-repository provider, so
+// fetching is best-effort even in "auto" mode: a missing "origin" remote is
+// not an error, only a genuine fetch failure (e.g. bad auth) is.
+type fetchMode string
+
+const (
+	fetchAuto  fetchMode = "auto"
+	fetchNever fetchMode = "never"
+)
+
+// Repository implements provider.Provider against a plain, local git
+// repository checked out at a filesystem path.
+type Repository struct {
+	gitPath       string
+	defaultBranch string
+	taggerName    string
+	taggerEmail   string
+	auth          transport.AuthMethod
+	signKey       *openpgp.Entity
+	signTags      signTagsMode
+	fetchMode     fetchMode
+	paths         []string
+	tagPrefix     string
+	branches      []branchConfig
+
+	repo *git.Repository
+}
+
+// branchConfig associates a branch (or glob pattern, e.g. "nested/release/*")
+// with the semantic-release channel it publishes to.
+type branchConfig struct {
+	Name       string `json:"name"`
+	Channel    string `json:"channel"`
+	Prerelease bool   `json:"prerelease"`
+}
+
+// Init opens the git repository at config["git_path"] (defaulting to the
+// current directory) and parses the tagger and authentication settings
+// used by CreateRelease.
+func (r *Repository) Init(config map[string]string) error {
+	r.gitPath = config["git_path"]
+	if r.gitPath == "" {
+		r.gitPath = "."
+	}
+
+	if err := r.ensureOpen(); err != nil {
+		return err
+	}
+
+	r.defaultBranch = config["default_branch"]
+	if r.defaultBranch == "" {
+		r.defaultBranch = defaultBranchName
+	}
+
+	r.taggerName = config["tagger_name"]
+	r.taggerEmail = config["tagger_email"]
+
+	auth, err := parseAuth(config)
+	if err != nil {
+		return err
+	}
+	r.auth = auth
+
+	r.signTags = signTagsMode(config["sign_tags"])
+	if r.signTags == "" {
+		r.signTags = defaultSignTagsMode
+	}
+	switch r.signTags {
+	case signTagsAlways, signTagsNever, signTagsIfKey:
+	default:
+		return fmt.Errorf("unknown sign_tags mode %q", config["sign_tags"])
+	}
+
+	signKey, err := parseSigningKey(config)
+	if err != nil {
+		return err
+	}
+	r.signKey = signKey
+
+	if r.signTags == signTagsAlways && r.signKey == nil {
+		return errors.New(`sign_tags is "always" but no signing key could be loaded`)
+	}
+
+	r.fetchMode = fetchMode(config["fetch"])
+	if r.fetchMode == "" {
+		r.fetchMode = defaultFetchMode
+	}
+	switch r.fetchMode {
+	case fetchAuto, fetchNever:
+	default:
+		return fmt.Errorf("unknown fetch mode %q", config["fetch"])
+	}
+
+	r.paths = parsePaths(config["paths"])
+	r.tagPrefix = config["tag_prefix"]
+
+	branches, err := parseBranches(config["branches"])
+	if err != nil {
+		return err
+	}
+	r.branches = branches
+
+	return nil
+}
+
+// parseBranches parses the "branches" config value, a JSON array of
+// {name, channel, prerelease} objects, in priority order. An empty value
+// means no channel configuration is in effect.
+func parseBranches(raw string) ([]branchConfig, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	var branches []branchConfig
+	if err := json.Unmarshal([]byte(raw), &branches); err != nil {
+		return nil, fmt.Errorf("could not parse branches config: %w", err)
+	}
+
+	return branches, nil
+}
+
+// matchBranch returns the branchConfig whose name matches branchName, either
+// exactly or as a glob pattern (e.g. "nested/release/*"), in config order.
+func (r *Repository) matchBranch(branchName string) (branchConfig, bool) {
+	for _, b := range r.branches {
+		if b.Name == branchName {
+			return b, true
+		}
+		if ok, _ := path.Match(b.Name, branchName); ok {
+			return b, true
+		}
+	}
+	return branchConfig{}, false
+}
+
+// parsePaths splits the comma-separated "paths" config value into a list of
+// path prefixes/globs used to scope a component's commits and releases
+// within a monorepo. An empty value means "no scoping".
+func parsePaths(paths string) []string {
+	if paths == "" {
+		return nil
+	}
+
+	var result []string
+	for _, p := range strings.Split(paths, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// matchesPaths reports whether name is covered by one of the configured
+// path prefixes/globs.
+func matchesPaths(paths []string, name string) bool {
+	for _, p := range paths {
+		if strings.ContainsAny(p, "*?[") {
+			if ok, _ := path.Match(p, name); ok {
+				return true
+			}
+			continue
+		}
+		if strings.HasPrefix(name, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// ensureOpen opens the repository at r.gitPath if it isn't already open,
+// reopening it lazily after a call to Close.
+func (r *Repository) ensureOpen() error {
+	if r.repo != nil {
+		return nil
+	}
+
+	repo, err := git.PlainOpen(r.gitPath)
+	if err != nil {
+		return errors.New("repository does not exist")
+	}
+	r.repo = repo
+
+	return nil
+}
+
+// Close releases the file handles held open by the underlying git storage.
+// A Repository remains usable after Close; public methods reopen the
+// repository lazily via ensureOpen.
+func (r *Repository) Close() error {
+	if r.repo == nil {
+		return nil
+	}
+
+	var closeErr error
+	if closer, ok := r.repo.Storer.(io.Closer); ok {
+		closeErr = closer.Close()
+	}
+	r.repo = nil
+
+	return closeErr
+}
+
+// parseAuth builds the transport.AuthMethod used for fetch/push operations
+// from the "auth" family of config keys. An empty or "none" mode is valid
+// for anonymous HTTPS remotes.
+func parseAuth(config map[string]string) (transport.AuthMethod, error) {
+	switch config["auth"] {
+	case "", "none":
+		return nil, nil
+	case "basic":
+		return parseBasicAuth(config)
+	case "token":
+		return parseTokenAuth(config)
+	case "ssh":
+		return parseSSHAuth(config)
+	default:
+		return nil, fmt.Errorf("unknown auth mode %q", config["auth"])
+	}
+}
+
+func parseBasicAuth(config map[string]string) (transport.AuthMethod, error) {
+	username := config["auth_username"]
+	password := config["auth_password"]
+	if username == "" || password == "" {
+		return nil, errors.New("auth_username and auth_password are required for basic auth")
+	}
+	return &http.BasicAuth{Username: username, Password: password}, nil
+}
+
+// parseTokenAuth builds a bearer-style auth method for hosts such as GitHub
+// or GitLab, which expect the token as the HTTP basic auth password.
+func parseTokenAuth(config map[string]string) (transport.AuthMethod, error) {
+	token := config["auth_token"]
+	if token == "" {
+		return nil, errors.New("auth_token is required for token auth")
+	}
+
+	username := config["auth_token_username"]
+	if username == "" {
+		username = defaultAuthTokenUsername
+	}
+
+	return &http.BasicAuth{Username: username, Password: token}, nil
+}
+
+// parseSSHAuth builds a public-key auth method from either an inline key
+// (auth_ssh_key) or a path to one on disk (auth_ssh_key_path), optionally
+// decrypted with auth_ssh_passphrase and pinned with auth_ssh_known_hosts.
+func parseSSHAuth(config map[string]string) (transport.AuthMethod, error) {
+	username := config["auth_ssh_username"]
+	if username == "" {
+		username = defaultAuthSSHUsername
+	}
+	passphrase := config["auth_ssh_passphrase"]
+
+	var auth *gitssh.PublicKeys
+	var err error
+	switch {
+	case config["auth_ssh_key_path"] != "":
+		auth, err = gitssh.NewPublicKeysFromFile(username, config["auth_ssh_key_path"], passphrase)
+	case config["auth_ssh_key"] != "":
+		auth, err = gitssh.NewPublicKeys(username, []byte(config["auth_ssh_key"]), passphrase)
+	default:
+		return nil, errors.New("auth_ssh_key_path or auth_ssh_key is required for ssh auth")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not load ssh key: %w", err)
+	}
+
+	if knownHosts := config["auth_ssh_known_hosts"]; knownHosts != "" {
+		callback, err := gitssh.NewKnownHostsCallback(knownHosts)
+		if err != nil {
+			return nil, fmt.Errorf("could not load known hosts file: %w", err)
+		}
+		auth.HostKeyCallback = callback
+	}
+
+	return auth, nil
+}
+
+// parseSigningKey loads the PGP key used to sign release tags, either from
+// an armored key file (signing_key_path) or the local GPG keyring
+// (signing_key_id). It returns a nil entity, with no error, when no signing
+// configuration is present.
+func parseSigningKey(config map[string]string) (*openpgp.Entity, error) {
+	var keyData []byte
+	switch {
+	case config["signing_key_path"] != "":
+		data, err := ioutil.ReadFile(config["signing_key_path"])
+		if err != nil {
+			return nil, fmt.Errorf("could not read signing key: %w", err)
+		}
+		keyData = data
+	case config["signing_key_id"] != "":
+		return loadSigningKeyFromKeyring(config["signing_key_id"])
+	default:
+		return nil, nil
+	}
+
+	entityList, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(keyData))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse signing key: %w", err)
+	}
+	if len(entityList) == 0 {
+		return nil, errors.New("signing key file does not contain any keys")
+	}
+
+	entity := entityList[0]
+	if err := decryptSigningKey(entity, config["signing_key_passphrase"]); err != nil {
+		return nil, err
+	}
+
+	return entity, nil
+}
+
+// decryptSigningKey decrypts an entity's private key (and any subkeys) with
+// passphrase. It is a no-op if the key isn't encrypted or no passphrase was
+// given.
+func decryptSigningKey(entity *openpgp.Entity, passphrase string) error {
+	if passphrase == "" {
+		return nil
+	}
+
+	if entity.PrivateKey != nil && entity.PrivateKey.Encrypted {
+		if err := entity.PrivateKey.Decrypt([]byte(passphrase)); err != nil {
+			return fmt.Errorf("could not decrypt signing key: %w", err)
+		}
+	}
+	for _, subkey := range entity.Subkeys {
+		if subkey.PrivateKey != nil && subkey.PrivateKey.Encrypted {
+			if err := subkey.PrivateKey.Decrypt([]byte(passphrase)); err != nil {
+				return fmt.Errorf("could not decrypt signing subkey: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// loadSigningKeyFromKeyring resolves a signing key by ID from the local GPG
+// keyring via the gpg CLI, since go-crypto has no keyring reader of its own.
+func loadSigningKeyFromKeyring(keyID string) (*openpgp.Entity, error) {
+	out, err := exec.Command("gpg", "--export-secret-key", "--armor", keyID).Output()
+	if err != nil {
+		return nil, fmt.Errorf("could not export signing key %q from gpg keyring: %w", keyID, err)
+	}
+
+	entityList, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(out))
+	if err != nil {
+		return nil, fmt.Errorf("could not parse signing key %q: %w", keyID, err)
+	}
+	if len(entityList) == 0 {
+		return nil, fmt.Errorf("no signing key found for id %q", keyID)
+	}
+
+	return entityList[0], nil
+}
+
+// GetInfo returns the repository metadata semantic-release needs to decide
+// how to cut a release. DefaultBranch reports the currently checked-out
+// branch, falling back to the configured default_branch if HEAD isn't on a
+// branch.
+//
+// provider.RepositoryInfo has no field for the resolved release channel, so
+// it can't be reported here; use CurrentChannel for that.
+func (r *Repository) GetInfo() (*provider.RepositoryInfo, error) {
+	if err := r.ensureOpen(); err != nil {
+		return nil, err
+	}
+
+	branch, err := r.currentBranchName()
+	if err != nil {
+		branch = r.defaultBranch
+	}
+
+	return &provider.RepositoryInfo{
+		DefaultBranch: branch,
+	}, nil
+}
+
+// currentBranchName returns the short name of the branch HEAD is pointing
+// at.
+func (r *Repository) currentBranchName() (string, error) {
+	head, err := r.repo.Head()
+	if err != nil {
+		return "", err
+	}
+	if !head.Name().IsBranch() {
+		return "", errors.New("HEAD does not point at a branch")
+	}
+	return head.Name().Short(), nil
+}
+
+// CurrentChannel resolves the release channel configured for the currently
+// checked-out branch. matched is false when no "branches" entry covers the
+// current branch.
+//
+// This is exposed as its own method, rather than folded into GetInfo,
+// because provider.RepositoryInfo (part of the provider.Provider interface)
+// has no channel field to carry it on.
+func (r *Repository) CurrentChannel() (channel string, prerelease bool, matched bool, err error) {
+	if err = r.ensureOpen(); err != nil {
+		return "", false, false, err
+	}
+
+	branchName, err := r.currentBranchName()
+	if err != nil {
+		return "", false, false, err
+	}
+
+	cfg, matched := r.matchBranch(branchName)
+	return cfg.Channel, cfg.Prerelease, matched, nil
+}
+
+// syncFromOrigin updates the local clone's refs and tags from the "origin"
+// remote using the configured auth, so GetCommits/GetReleases see history
+// and releases that only exist on the remote (e.g. an SSH-only host this
+// process never pushed to itself). It is a no-op when fetchMode is
+// fetchNever, and tolerates there being no "origin" remote at all, since
+// Repository also supports plain local-only repositories.
+func (r *Repository) syncFromOrigin() error {
+	if r.fetchMode == fetchNever {
+		return nil
+	}
+
+	err := r.repo.Fetch(&git.FetchOptions{
+		RemoteName: "origin",
+		Auth:       r.auth,
+		Tags:       git.AllTags,
+	})
+	switch {
+	case err == nil, err == git.NoErrAlreadyUpToDate, err == git.ErrRemoteNotFound:
+		return nil
+	default:
+		return fmt.Errorf("could not fetch from origin: %w", err)
+	}
+}
+
+// GetCommits returns the commits reachable from toRef, stopping once fromSHA
+// is reached (fromSHA itself is excluded). An empty fromSHA returns the full
+// history of toRef. When "paths" is configured, only commits that change a
+// file covered by those paths are returned.
+func (r *Repository) GetCommits(fromSHA, toRef string) ([]*semrel.RawCommit, error) {
+	if err := r.ensureOpen(); err != nil {
+		return nil, err
+	}
+	if err := r.syncFromOrigin(); err != nil {
+		return nil, err
+	}
+
+	toHash, err := r.resolveRef(toRef)
+	if err != nil {
+		return nil, err
+	}
+
+	cIter, err := r.repo.Log(&git.LogOptions{From: toHash})
+	if err != nil {
+		return nil, err
+	}
+	defer cIter.Close()
+
+	commits := []*semrel.RawCommit{}
+	err = cIter.ForEach(func(c *object.Commit) error {
+		if fromSHA != "" && c.Hash.String() == fromSHA {
+			return storer.ErrStop
+		}
+
+		touches, err := r.commitTouchesPaths(c)
+		if err != nil {
+			return err
+		}
+		if !touches {
+			return nil
+		}
+
+		commits = append(commits, &semrel.RawCommit{
+			SHA:        c.Hash.String(),
+			RawMessage: c.Message,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return commits, nil
+}
+
+// commitTouchesPaths reports whether c changes any file covered by the
+// configured "paths". When no paths are configured, every commit matches.
+func (r *Repository) commitTouchesPaths(c *object.Commit) (bool, error) {
+	if len(r.paths) == 0 {
+		return true, nil
+	}
+
+	tree, err := c.Tree()
+	if err != nil {
+		return false, err
+	}
+
+	var parentTree *object.Tree
+	if c.NumParents() > 0 {
+		parent, err := c.Parent(0)
+		if err != nil {
+			return false, err
+		}
+		parentTree, err = parent.Tree()
+		if err != nil {
+			return false, err
+		}
+	}
+
+	changes, err := object.DiffTree(parentTree, tree)
+	if err != nil {
+		return false, err
+	}
+
+	for _, change := range changes {
+		if matchesPaths(r.paths, change.From.Name) || matchesPaths(r.paths, change.To.Name) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// originRemoteRefPrefix is where a real "git fetch origin" leaves a remote's
+// branches, since a CI checkout typically only has the branch actually being
+// built as a local ref (refs/heads/*).
+const originRemoteRefPrefix = "refs/remotes/origin/"
+
+// branchHeads returns the head commit of every branch this repository knows
+// about, keyed by short name (e.g. "main", not "origin/main"). This merges
+// refs/heads/* with refs/remotes/origin/*, since a channel branch named in
+// "branches" usually only exists as a remote-tracking ref after
+// syncFromOrigin, never checked out locally. A local branch wins over a
+// same-named remote-tracking one.
+func (r *Repository) branchHeads() (map[string]plumbing.Hash, error) {
+	heads := map[string]plumbing.Hash{}
+
+	localRefs, err := r.repo.Branches()
+	if err != nil {
+		return nil, err
+	}
+	if err := localRefs.ForEach(func(ref *plumbing.Reference) error {
+		heads[ref.Name().Short()] = ref.Hash()
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	allRefs, err := r.repo.References()
+	if err != nil {
+		return nil, err
+	}
+	err = allRefs.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name().String()
+		if !strings.HasPrefix(name, originRemoteRefPrefix) {
+			return nil
+		}
+		short := strings.TrimPrefix(name, originRemoteRefPrefix)
+		if short == "HEAD" {
+			return nil
+		}
+		if _, ok := heads[short]; !ok {
+			heads[short] = ref.Hash()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return heads, nil
+}
+
+// channelForCommit resolves the channel of whichever configured branch's
+// history reaches commitHash, i.e. the first configured branch (in config
+// order) for which commitHash is an ancestor of its head.
+func (r *Repository) channelForCommit(commitHash plumbing.Hash) (channel string, prerelease bool, matched bool, err error) {
+	if len(r.branches) == 0 {
+		return "", false, false, nil
+	}
+
+	heads, err := r.branchHeads()
+	if err != nil {
+		return "", false, false, err
+	}
+
+	for _, cfg := range r.branches {
+		for name, hash := range heads {
+			if ok, _ := path.Match(cfg.Name, name); cfg.Name != name && !ok {
+				continue
+			}
+
+			reachable, rerr := r.isAncestorOf(commitHash, hash)
+			if rerr != nil {
+				return "", false, false, rerr
+			}
+			if reachable {
+				return cfg.Channel, cfg.Prerelease, true, nil
+			}
+		}
+	}
+
+	return "", false, false, nil
+}
+
+// ReleaseChannel resolves the channel of the branch that produced the commit
+// at sha, per the "branches" config. matched is false when no configured
+// branch's history reaches sha.
+func (r *Repository) ReleaseChannel(sha string) (channel string, prerelease bool, matched bool, err error) {
+	if err = r.ensureOpen(); err != nil {
+		return "", false, false, err
+	}
+	return r.channelForCommit(plumbing.NewHash(sha))
+}
+
+// isAncestorOf reports whether ancestor is reachable from descendant.
+func (r *Repository) isAncestorOf(ancestor, descendant plumbing.Hash) (bool, error) {
+	if ancestor == descendant {
+		return true, nil
+	}
+
+	cIter, err := r.repo.Log(&git.LogOptions{From: descendant})
+	if err != nil {
+		return false, err
+	}
+	defer cIter.Close()
+
+	found := false
+	err = cIter.ForEach(func(c *object.Commit) error {
+		if c.Hash == ancestor {
+			found = true
+			return storer.ErrStop
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return found, nil
+}
+
+// checkReachableFromBranch refuses to release a SHA that isn't reachable
+// from branchName's head, preventing a release from being tagged on behalf
+// of the wrong channel. branchName comes from the release config rather
+// than HEAD, since CI may run with a detached HEAD or have a different
+// branch checked out than the one actually being released.
+func (r *Repository) checkReachableFromBranch(sha, branchName string) error {
+	heads, err := r.branchHeads()
+	if err != nil {
+		return err
+	}
+	branchHash, ok := heads[branchName]
+	if !ok {
+		return fmt.Errorf("could not resolve branch %q: not found locally or on origin", branchName)
+	}
+
+	reachable, err := r.isAncestorOf(plumbing.NewHash(sha), branchHash)
+	if err != nil {
+		return err
+	}
+	if !reachable {
+		return fmt.Errorf("sha %q is not reachable from branch %q", sha, branchName)
+	}
+
+	return nil
+}
+
+// resolveRef resolves a branch, tag or raw SHA to a commit hash.
+func (r *Repository) resolveRef(ref string) (plumbing.Hash, error) {
+	hash, err := r.repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("could not resolve ref %q: %w", ref, err)
+	}
+	return *hash, nil
+}
+
+// GetReleases returns every tag that parses as a semantic version, optionally
+// restricted to tag names matching the regular expression re. Only tags that
+// start with the configured tag_prefix are considered, and the prefix is
+// stripped before parsing. When "branches" is configured, tags whose commit
+// isn't reachable from any configured branch are left out.
+func (r *Repository) GetReleases(re string) ([]*semrel.Release, error) {
+	if err := r.ensureOpen(); err != nil {
+		return nil, err
+	}
+	if err := r.syncFromOrigin(); err != nil {
+		return nil, err
+	}
+
+	var filter *regexp.Regexp
+	if re != "" {
+		var err error
+		filter, err = regexp.Compile(re)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tagRefs, err := r.repo.Tags()
+	if err != nil {
+		return nil, err
+	}
+
+	releases := []*semrel.Release{}
+	err = tagRefs.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name().Short()
+		if filter != nil && !filter.MatchString(name) {
+			return nil
+		}
+		if !strings.HasPrefix(name, r.tagPrefix) {
+			return nil
+		}
+
+		version, err := semver.NewVersion(strings.TrimPrefix(name, r.tagPrefix))
+		if err != nil {
+			// Not every tag is necessarily a release, skip silently.
+			return nil
+		}
+
+		hash := ref.Hash()
+		if tagObj, err := r.repo.TagObject(hash); err == nil {
+			hash = tagObj.Target
+		}
+
+		if len(r.branches) > 0 {
+			_, _, matched, err := r.channelForCommit(hash)
+			if err != nil {
+				return err
+			}
+			if !matched {
+				return nil
+			}
+		}
+
+		releases = append(releases, &semrel.Release{
+			SHA:     hash.String(),
+			Version: version.String(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return releases, nil
+}
+
+// CreateRelease tags SHA with the release version and pushes the tag to the
+// configured remote.
+func (r *Repository) CreateRelease(release *provider.CreateReleaseConfig) error {
+	if err := r.ensureOpen(); err != nil {
+		return err
+	}
+
+	if len(r.branches) > 0 {
+		if err := r.checkReachableFromBranch(release.SHA, release.Branch); err != nil {
+			return err
+		}
+	}
+
+	tagName := r.tagPrefix + "v" + release.NewVersion
+	message := release.Changelog
+	if !strings.HasSuffix(message, "\n") {
+		message += "\n"
+	}
+
+	tagOpts := &git.CreateTagOptions{
+		Tagger: &object.Signature{
+			Name:  r.taggerName,
+			Email: r.taggerEmail,
+			When:  time.Now(),
+		},
+		Message: message,
+	}
+	if r.signTags != signTagsNever && r.signKey != nil {
+		tagOpts.SignKey = r.signKey
+	}
+
+	_, err := r.repo.CreateTag(tagName, plumbing.NewHash(release.SHA), tagOpts)
+	if err != nil {
+		return fmt.Errorf("could not create tag %q: %w", tagName, err)
+	}
+
+	refSpec := config.RefSpec(fmt.Sprintf("refs/tags/%s:refs/tags/%s", tagName, tagName))
+	err = r.repo.Push(&git.PushOptions{
+		RemoteName: "origin",
+		RefSpecs:   []config.RefSpec{refSpec},
+		Auth:       r.auth,
+	})
+	if err != nil {
+		return fmt.Errorf("could not push tag %q: %w", tagName, err)
+	}
+
+	return nil
+}
+
+// Name identifies this provider to semantic-release.
+func (r *Repository) Name() string {
+	return "git"
+}
+
+// Version reports the provider's own version.
+func (r *Repository) Version() string {
+	return "dev"
+}
@@ -1,12 +1,22 @@
 package provider
 
 import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
 	"fmt"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
@@ -17,16 +27,26 @@ import (
 )
 
 var testGitPath string
+var testReleaseOnlyHash plumbing.Hash
 
 func TestGit(t *testing.T) {
 	var err error
 	testGitPath, err = setupRepo()
 	require.NoError(t, err)
 	t.Run("NewRepository", newRepository)
+	t.Run("NewRepositoryAuthModes", newRepositoryAuthModes)
 	t.Run("GetInfo", getInfo)
 	t.Run("GetReleases", getReleases)
 	t.Run("GetCommits", getCommits)
+	t.Run("GetCommitsMonorepo", getCommitsMonorepo)
 	t.Run("CreateRelease", createRelease)
+	t.Run("CreateReleaseTagPrefix", createReleaseTagPrefix)
+	t.Run("CreateReleaseSigned", createReleaseSigned)
+	t.Run("NewRepositorySigningValidation", newRepositorySigningValidation)
+	t.Run("CloseRepository", closeRepository)
+	t.Run("ReleaseChannels", releaseChannels)
+	t.Run("CurrentChannel", currentChannel)
+	t.Run("ChannelRemoteTrackingBranch", channelRemoteTrackingBranch)
 }
 
 func newRepository(t *testing.T) {
@@ -53,6 +73,62 @@ func newRepository(t *testing.T) {
 	require.NotNil(repo.auth)
 }
 
+func newRepositoryAuthModes(t *testing.T) {
+	require := require.New(t)
+
+	repo := &Repository{}
+	err := repo.Init(map[string]string{
+		"git_path": testGitPath,
+		"auth":     "none",
+	})
+	require.NoError(err)
+	require.Nil(repo.auth)
+
+	repo = &Repository{}
+	err = repo.Init(map[string]string{
+		"git_path":   testGitPath,
+		"auth":       "token",
+		"auth_token": "abc123",
+	})
+	require.NoError(err)
+	require.NotNil(repo.auth)
+
+	repo = &Repository{}
+	err = repo.Init(map[string]string{
+		"git_path": testGitPath,
+		"auth":     "token",
+	})
+	require.EqualError(err, "auth_token is required for token auth")
+
+	keyPath, passphrase := generateTestSSHKey(t)
+
+	repo = &Repository{}
+	err = repo.Init(map[string]string{
+		"git_path":            testGitPath,
+		"auth":                "ssh",
+		"auth_ssh_key_path":   keyPath,
+		"auth_ssh_passphrase": passphrase,
+	})
+	require.NoError(err)
+	require.NotNil(repo.auth)
+
+	repo = &Repository{}
+	err = repo.Init(map[string]string{
+		"git_path": testGitPath,
+		"auth":     "ssh",
+	})
+	require.EqualError(err, "auth_ssh_key_path or auth_ssh_key is required for ssh auth")
+
+	repo = &Repository{}
+	err = repo.Init(map[string]string{
+		"git_path":            testGitPath,
+		"auth":                "ssh",
+		"auth_ssh_key_path":   keyPath,
+		"auth_ssh_passphrase": "wrong-passphrase",
+	})
+	require.Error(err)
+}
+
 func setupRepo() (string, error) {
 	dir, err := ioutil.TempDir("", "provider-git")
 	if err != nil {
@@ -82,11 +158,18 @@ func setupRepo() (string, error) {
 	}
 	versionCount := 0
 	betaCount := 1
+	var forkPoint, releaseOnlyPoint plumbing.Hash
 	for i := 0; i < 100; i++ {
 		commit, err := w.Commit(fmt.Sprintf("feat: commit %d", i), &git.CommitOptions{Author: author})
 		if err != nil {
 			return "", err
 		}
+		if i == 30 {
+			releaseOnlyPoint = commit
+		}
+		if i == 50 {
+			forkPoint = commit
+		}
 		if i%10 == 0 {
 			if _, err := repo.CreateTag(fmt.Sprintf("v1.%d.0", versionCount), commit, nil); err != nil {
 				return "", err
@@ -101,6 +184,32 @@ func setupRepo() (string, error) {
 		}
 	}
 
+	for _, component := range []string{"frontend", "backend"} {
+		componentDir := filepath.Join(dir, component)
+		if err := os.MkdirAll(componentDir, 0755); err != nil {
+			return "", err
+		}
+		for j := 0; j < 3; j++ {
+			relPath := filepath.Join(component, fmt.Sprintf("file%d.txt", j))
+			content := fmt.Sprintf("%s content %d", component, j)
+			if err := ioutil.WriteFile(filepath.Join(dir, relPath), []byte(content), 0644); err != nil {
+				return "", err
+			}
+			if _, err := w.Add(relPath); err != nil {
+				return "", err
+			}
+			commit, err := w.Commit(fmt.Sprintf("feat(%s): change %d", component, j), &git.CommitOptions{Author: author})
+			if err != nil {
+				return "", err
+			}
+			if j == 2 {
+				if _, err := repo.CreateTag(fmt.Sprintf("%s/v1.0.0", component), commit, nil); err != nil {
+					return "", err
+				}
+			}
+		}
+	}
+
 	err = w.Checkout(&git.CheckoutOptions{
 		Branch: plumbing.NewBranchReferenceName("new-fix"),
 		Create: true,
@@ -116,6 +225,39 @@ func setupRepo() (string, error) {
 		return "", err
 	}
 
+	// "abandoned" forks from partway through master's history and is never
+	// merged back or configured as a channel branch, so its tag is
+	// reachable from neither master nor new-fix: this is what exercises
+	// GetReleases' "not reachable from any configured branch" exclusion.
+	err = w.Checkout(&git.CheckoutOptions{
+		Hash:   forkPoint,
+		Branch: plumbing.NewBranchReferenceName("abandoned"),
+		Create: true,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	abandonedCommit, err := w.Commit("feat: abandoned work", &git.CommitOptions{Author: author})
+	if err != nil {
+		return "", err
+	}
+	if _, err := repo.CreateTag("v9.9.9", abandonedCommit, nil); err != nil {
+		return "", err
+	}
+	if err = w.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("master")}); err != nil {
+		return "", err
+	}
+
+	// "release-only" simulates a realistic CI checkout where a channel
+	// branch is known only as a remote-tracking ref (as left behind by a
+	// real "git fetch origin"), never checked out locally.
+	testReleaseOnlyHash = releaseOnlyPoint
+	remoteRef := plumbing.NewHashReference(plumbing.ReferenceName(originRemoteRefPrefix+"release-only"), releaseOnlyPoint)
+	if err := repo.Storer.SetReference(remoteRef); err != nil {
+		return "", err
+	}
+
 	err = repo.Push(&git.PushOptions{
 		RemoteName: "origin",
 		RefSpecs: []config.RefSpec{
@@ -134,6 +276,61 @@ func setupRepo() (string, error) {
 	return dir, nil
 }
 
+// generateTestSSHKey writes a passphrase-encrypted RSA private key to a
+// temp file and returns its path along with the passphrase.
+func generateTestSSHKey(t *testing.T) (string, string) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	const passphrase = "test-passphrase"
+	block, err := x509.EncryptPEMBlock( //nolint:staticcheck // only used to fabricate a test fixture
+		rand.Reader,
+		"RSA PRIVATE KEY",
+		x509.MarshalPKCS1PrivateKey(key),
+		[]byte(passphrase),
+		x509.PEMCipherAES256,
+	)
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "provider-git-ssh-key")
+	require.NoError(t, err)
+
+	keyPath := filepath.Join(dir, "id_rsa")
+	require.NoError(t, ioutil.WriteFile(keyPath, pem.EncodeToMemory(block), 0600))
+
+	return keyPath, passphrase
+}
+
+// generateTestPGPKey creates an ephemeral in-memory PGP keypair and returns
+// the path to its armored private key plus its armored public key.
+func generateTestPGPKey(t *testing.T) (string, string) {
+	t.Helper()
+
+	entity, err := openpgp.NewEntity("Test Signer", "", "signer@test.com", nil)
+	require.NoError(t, err)
+
+	var privBuf bytes.Buffer
+	privWriter, err := armor.Encode(&privBuf, openpgp.PrivateKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.SerializePrivate(privWriter, nil))
+	require.NoError(t, privWriter.Close())
+
+	dir, err := ioutil.TempDir("", "provider-git-pgp-key")
+	require.NoError(t, err)
+	keyPath := filepath.Join(dir, "signing.key")
+	require.NoError(t, ioutil.WriteFile(keyPath, privBuf.Bytes(), 0600))
+
+	var pubBuf bytes.Buffer
+	pubWriter, err := armor.Encode(&pubBuf, openpgp.PublicKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.Serialize(pubWriter))
+	require.NoError(t, pubWriter.Close())
+
+	return keyPath, pubBuf.String()
+}
+
 func createRepo() (*Repository, error) {
 	repo := &Repository{}
 	err := repo.Init(map[string]string{
@@ -164,11 +361,59 @@ func getCommits(t *testing.T) {
 	require.NoError(err)
 	commits, err := repo.GetCommits("", "master")
 	require.NoError(err)
-	require.Len(commits, 100)
+	require.Len(commits, 106)
 
 	for _, c := range commits {
-		require.True(strings.HasPrefix(c.RawMessage, "feat: commit"))
+		require.True(strings.HasPrefix(c.RawMessage, "feat"))
+	}
+}
+
+func getCommitsMonorepo(t *testing.T) {
+	require := require.New(t)
+
+	frontend := &Repository{}
+	require.NoError(frontend.Init(map[string]string{
+		"git_path":      testGitPath,
+		"auth":          "basic",
+		"auth_username": "test",
+		"auth_password": "test",
+		"paths":         "frontend/",
+		"tag_prefix":    "frontend/",
+	}))
+
+	backend := &Repository{}
+	require.NoError(backend.Init(map[string]string{
+		"git_path":      testGitPath,
+		"auth":          "basic",
+		"auth_username": "test",
+		"auth_password": "test",
+		"paths":         "backend/",
+		"tag_prefix":    "backend/",
+	}))
+
+	frontendCommits, err := frontend.GetCommits("", "master")
+	require.NoError(err)
+	require.Len(frontendCommits, 3)
+	for _, c := range frontendCommits {
+		require.True(strings.HasPrefix(c.RawMessage, "feat(frontend)"))
+	}
+
+	backendCommits, err := backend.GetCommits("", "master")
+	require.NoError(err)
+	require.Len(backendCommits, 3)
+	for _, c := range backendCommits {
+		require.True(strings.HasPrefix(c.RawMessage, "feat(backend)"))
 	}
+
+	frontendReleases, err := frontend.GetReleases("")
+	require.NoError(err)
+	require.Len(frontendReleases, 1)
+	require.Equal("1.0.0", frontendReleases[0].Version)
+
+	backendReleases, err := backend.GetReleases("")
+	require.NoError(err)
+	require.Len(backendReleases, 1)
+	require.Equal("1.0.0", backendReleases[0].Version)
 }
 
 func createRelease(t *testing.T) {
@@ -197,6 +442,267 @@ func createRelease(t *testing.T) {
 	require.Equal("new feature\n", tagObj.Message)
 }
 
+// createReleaseTagPrefix checks that CreateRelease, like GetReleases,
+// honors a configured tag_prefix when naming the tag it creates.
+func createReleaseTagPrefix(t *testing.T) {
+	require := require.New(t)
+
+	repo := &Repository{}
+	err := repo.Init(map[string]string{
+		"git_path":      testGitPath,
+		"auth":          "basic",
+		"auth_username": "test",
+		"auth_password": "test",
+		"paths":         "frontend/",
+		"tag_prefix":    "frontend/",
+	})
+	require.NoError(err)
+
+	gRepo, err := git.PlainOpen(testGitPath)
+	require.NoError(err)
+	head, err := gRepo.Head()
+	require.NoError(err)
+
+	err = repo.CreateRelease(&provider.CreateReleaseConfig{
+		NewVersion: "2.0.0",
+		SHA:        head.Hash().String(),
+		Changelog:  "new frontend feature",
+	})
+	require.NoError(err)
+
+	tagRef, err := gRepo.Tag("frontend/v2.0.0")
+	require.NoError(err)
+
+	tagObj, err := gRepo.TagObject(tagRef.Hash())
+	require.NoError(err)
+	require.Equal("new frontend feature\n", tagObj.Message)
+}
+
+func createReleaseSigned(t *testing.T) {
+	require := require.New(t)
+
+	keyPath, publicKey := generateTestPGPKey(t)
+
+	repo := &Repository{}
+	err := repo.Init(map[string]string{
+		"git_path":         testGitPath,
+		"auth":             "basic",
+		"auth_username":    "test",
+		"auth_password":    "test",
+		"signing_key_path": keyPath,
+		"sign_tags":        "always",
+	})
+	require.NoError(err)
+
+	gRepo, err := git.PlainOpen(testGitPath)
+	require.NoError(err)
+	head, err := gRepo.Head()
+	require.NoError(err)
+
+	err = repo.CreateRelease(&provider.CreateReleaseConfig{
+		NewVersion: "3.0.0",
+		SHA:        head.Hash().String(),
+		Changelog:  "signed release",
+	})
+	require.NoError(err)
+
+	tagRef, err := gRepo.Tag("v3.0.0")
+	require.NoError(err)
+
+	tagObj, err := gRepo.TagObject(tagRef.Hash())
+	require.NoError(err)
+	require.NotEmpty(tagObj.PGPSignature)
+
+	signer, err := tagObj.Verify(publicKey)
+	require.NoError(err)
+	require.Contains(signer.Identities, "Test Signer <signer@test.com>")
+}
+
+func newRepositorySigningValidation(t *testing.T) {
+	require := require.New(t)
+
+	repo := &Repository{}
+	err := repo.Init(map[string]string{
+		"git_path":  testGitPath,
+		"sign_tags": "always",
+	})
+	require.EqualError(err, `sign_tags is "always" but no signing key could be loaded`)
+
+	repo = &Repository{}
+	err = repo.Init(map[string]string{
+		"git_path":  testGitPath,
+		"sign_tags": "bogus",
+	})
+	require.Error(err)
+}
+
+// closeRepository opens and closes the repository many times and asserts
+// that doing so does not leak file descriptors (Linux only; there's no
+// portable way to count open FDs from within the test process).
+func closeRepository(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("file descriptor count is only checked on linux")
+	}
+
+	require := require.New(t)
+
+	countFDs := func() int {
+		entries, err := ioutil.ReadDir("/proc/self/fd")
+		require.NoError(err)
+		return len(entries)
+	}
+
+	initAndClose := func(repo *Repository) {
+		err := repo.Init(map[string]string{
+			"git_path":      testGitPath,
+			"auth":          "basic",
+			"auth_username": "test",
+			"auth_password": "test",
+		})
+		require.NoError(err)
+		_, err = repo.GetInfo()
+		require.NoError(err)
+		require.NoError(repo.Close())
+	}
+
+	repo := &Repository{}
+	for i := 0; i < 50; i++ {
+		initAndClose(repo)
+	}
+
+	before := countFDs()
+	for i := 0; i < 20; i++ {
+		initAndClose(repo)
+	}
+	after := countFDs()
+
+	require.LessOrEqual(after, before+2)
+
+	// The repository is reopened lazily, so it stays usable after Close.
+	info, err := repo.GetInfo()
+	require.NoError(err)
+	require.Equal("master", info.DefaultBranch)
+}
+
+// channelRemoteTrackingBranch asserts that a channel branch known only as a
+// remote-tracking ref (refs/remotes/origin/*) — as left behind by a real
+// "git fetch", never checked out locally — is still resolved by both
+// ReleaseChannel and CreateRelease's reachability check.
+func channelRemoteTrackingBranch(t *testing.T) {
+	require := require.New(t)
+
+	repo := &Repository{}
+	err := repo.Init(map[string]string{
+		"git_path":      testGitPath,
+		"auth":          "basic",
+		"auth_username": "test",
+		"auth_password": "test",
+		"branches":      `[{"name":"release-only","channel":"stable","prerelease":false}]`,
+	})
+	require.NoError(err)
+
+	channel, prerelease, matched, err := repo.ReleaseChannel(testReleaseOnlyHash.String())
+	require.NoError(err)
+	require.True(matched)
+	require.Equal("stable", channel)
+	require.False(prerelease)
+
+	err = repo.CreateRelease(&provider.CreateReleaseConfig{
+		NewVersion: "4.0.0",
+		SHA:        testReleaseOnlyHash.String(),
+		Branch:     "release-only",
+		Changelog:  "release from a remote-tracking-only branch",
+	})
+	require.NoError(err)
+
+	gRepo, err := git.PlainOpen(testGitPath)
+	require.NoError(err)
+	_, err = gRepo.Tag("v4.0.0")
+	require.NoError(err)
+}
+
+func releaseChannels(t *testing.T) {
+	require := require.New(t)
+
+	repo := &Repository{}
+	err := repo.Init(map[string]string{
+		"git_path":      testGitPath,
+		"auth":          "basic",
+		"auth_username": "test",
+		"auth_password": "test",
+		"branches":      `[{"name":"new-fix","channel":"beta","prerelease":true}]`,
+	})
+	require.NoError(err)
+
+	gRepo, err := git.PlainOpen(testGitPath)
+	require.NoError(err)
+	newFixRef, err := gRepo.Reference(plumbing.NewBranchReferenceName("new-fix"), true)
+	require.NoError(err)
+
+	channel, prerelease, matched, err := repo.ReleaseChannel(newFixRef.Hash().String())
+	require.NoError(err)
+	require.True(matched)
+	require.Equal("beta", channel)
+	require.True(prerelease)
+
+	// The 20 "v2.0.0-beta.*" tags plus the "v2.0.0" tag that CreateRelease
+	// put on master's head, which is an ancestor of "new-fix".
+	releases, err := repo.GetReleases("^v2")
+	require.NoError(err)
+	require.Len(releases, 21)
+	for _, release := range releases {
+		channel, _, matched, err := repo.ReleaseChannel(release.SHA)
+		require.NoError(err)
+		require.True(matched)
+		require.Equal("beta", channel)
+	}
+
+	// "v9.9.9" lives on the "abandoned" branch, which isn't reachable from
+	// "new-fix" (the only configured channel branch) or from anywhere else
+	// configured, so it must be excluded from GetReleases and unmatched by
+	// ReleaseChannel.
+	releases, err = repo.GetReleases("^v9")
+	require.NoError(err)
+	require.Empty(releases)
+
+	abandonedRef, err := gRepo.Reference(plumbing.NewBranchReferenceName("abandoned"), true)
+	require.NoError(err)
+	_, _, matched, err = repo.ReleaseChannel(abandonedRef.Hash().String())
+	require.NoError(err)
+	require.False(matched)
+}
+
+// currentChannel checks out the "beta"-channel branch and asserts that
+// CurrentChannel resolves it, since GetInfo's RepositoryInfo has nowhere to
+// report it.
+func currentChannel(t *testing.T) {
+	require := require.New(t)
+
+	gRepo, err := git.PlainOpen(testGitPath)
+	require.NoError(err)
+	w, err := gRepo.Worktree()
+	require.NoError(err)
+	require.NoError(w.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("new-fix")}))
+	defer func() {
+		require.NoError(w.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("master")}))
+	}()
+
+	repo := &Repository{}
+	require.NoError(repo.Init(map[string]string{
+		"git_path":      testGitPath,
+		"auth":          "basic",
+		"auth_username": "test",
+		"auth_password": "test",
+		"branches":      `[{"name":"new-fix","channel":"beta","prerelease":true}]`,
+	}))
+
+	channel, prerelease, matched, err := repo.CurrentChannel()
+	require.NoError(err)
+	require.True(matched)
+	require.Equal("beta", channel)
+	require.True(prerelease)
+}
+
 func getReleases(t *testing.T) {
 	require := require.New(t)
 	repo, err := createRepo()
@@ -204,7 +710,7 @@ func getReleases(t *testing.T) {
 
 	releases, err := repo.GetReleases("")
 	require.NoError(err)
-	require.Len(releases, 30)
+	require.Len(releases, 31)
 
 	releases, err = repo.GetReleases("^v2")
 	require.NoError(err)